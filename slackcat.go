@@ -2,20 +2,37 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/crewjam/slackcat/slackio"
+	"github.com/crewjam/slackcat/slackio/bridge"
 )
 
 func main() {
 	token := flag.String("token", "", "Slack Token")
 	endpoint := flag.String("endpoint", "", "Slack URL (may be empty)")
-	channel := flag.String("channel", "general", "The name of the slack channel")
+	channel := flag.String("channel", "general", "The name of the slack channel(s) to use, comma-separated (also accepts DMs, addressed by user ID)")
 	doTee := flag.Bool("tee", false, "tee stdin to both stdout and slack")
 	doRead := flag.Bool("read", false, "only read from slack, don't also write. Default is to both read and write.")
 	doWrite := flag.Bool("write", false, "only write to slack, don't also read. Default is to both read and write.")
+	bridgeSpec := flag.String("bridge", "", "relay messages between the slack channel and another transport, "+
+		"specified as \"irc|<addr>|<nick>|<channel>\" or \"webhook|<listen-addr>|<path>|<outgoing-url>\"")
+	uploadThreshold := flag.Int("upload-threshold", 3500, "writes larger than this many bytes are uploaded as a file instead of posted as a message; 0 disables auto-upload")
+	uploadFiletype := flag.String("upload-filetype", "text", "Slack filetype to use for auto-uploaded writes")
+	transport := flag.String("transport", "rtm", "how to connect to slack: \"rtm\" (default) or \"events\" for the Events API")
+	signingSecret := flag.String("signing-secret", "", "Slack signing secret, used to verify -transport=events callbacks")
+	listenAddr := flag.String("listen-addr", ":8080", "address the -transport=events HTTP server listens on")
+	eventsPath := flag.String("events-path", "/slack/events", "HTTP path the -transport=events server expects event_callback posts on")
+	match := flag.String("match", "", "only relay messages matching this regular expression")
+	exclude := flag.String("exclude", "", "drop messages matching this regular expression")
+	mentionOnly := flag.Bool("mention-only", false, "only relay messages that @-mention the bot's own user")
+	rate := flag.String("rate", "", "rate-limit writes to slack, as \"<msgs-per-sec>:<burst>\", e.g. \"1:5\"")
 
 	flag.Parse()
 	if !*doRead && !*doWrite {
@@ -27,7 +44,61 @@ func main() {
 		*doRead = false
 	}
 
-	s, err := slackio.New(*SlackURL, *SlackToken, *Channel)
+	if *bridgeSpec != "" {
+		if err := runBridge(*endpoint, *token, *channel, *bridgeSpec); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	opts := []slackio.Option{
+		slackio.WithUploadThreshold(*uploadThreshold),
+		slackio.WithUploadFiletype(*uploadFiletype),
+	}
+	if *match != "" {
+		re, err := regexp.Compile(*match)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, slackio.WithIncludeRegex(re))
+	}
+	if *exclude != "" {
+		re, err := regexp.Compile(*exclude)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, slackio.WithExcludeRegex(re))
+	}
+	if *mentionOnly {
+		opts = append(opts, slackio.WithMentionOnly())
+	}
+	if *rate != "" {
+		msgsPerSec, burst, err := parseRate(*rate)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, slackio.WithRateLimit(msgsPerSec, burst))
+	}
+
+	channels := strings.Split(*channel, ",")
+
+	var s io.ReadWriteCloser
+	var err error
+	switch *transport {
+	case "rtm":
+		s, err = slackio.NewReaderWriter(*endpoint, *token, channels, opts...)
+	case "events":
+		s, err = slackio.NewEventsReaderWriter(slackio.EventsConfig{
+			URL:           *endpoint,
+			Token:         *token,
+			Channels:      channels,
+			SigningSecret: *signingSecret,
+			ListenAddr:    *listenAddr,
+			Path:          *eventsPath,
+		}, opts...)
+	default:
+		err = fmt.Errorf("invalid -transport %q, expected \"rtm\" or \"events\"", *transport)
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -71,3 +142,55 @@ func main() {
 		panic(err)
 	}
 }
+
+// parseRate parses the "<msgs-per-sec>:<burst>" syntax accepted by -rate.
+func parseRate(spec string) (msgsPerSec float64, burst int, err error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf(`invalid -rate %q, expected "<msgs-per-sec>:<burst>"`, spec)
+	}
+	msgsPerSec, err = strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid -rate %q: %s", spec, err)
+	}
+	burst, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid -rate %q: %s", spec, err)
+	}
+	return msgsPerSec, burst, nil
+}
+
+// runBridge parses spec and relays messages between the slack channel
+// and the transport it describes until one side closes.
+func runBridge(endpoint, token, channel, spec string) error {
+	parts := strings.Split(spec, "|")
+	if len(parts) == 0 {
+		return fmt.Errorf("invalid -bridge spec %q", spec)
+	}
+
+	slackTransport, err := bridge.NewSlackTransport(endpoint, token, channel)
+	if err != nil {
+		return err
+	}
+
+	var other bridge.Transport
+	switch parts[0] {
+	case "irc":
+		if len(parts) != 4 {
+			return fmt.Errorf(`invalid -bridge spec %q, expected "irc|<addr>|<nick>|<channel>"`, spec)
+		}
+		other, err = bridge.NewIRCTransport(parts[1], parts[2], parts[3])
+	case "webhook":
+		if len(parts) != 4 {
+			return fmt.Errorf(`invalid -bridge spec %q, expected "webhook|<listen-addr>|<path>|<outgoing-url>"`, spec)
+		}
+		other, err = bridge.NewWebhookTransport(parts[1], parts[2], parts[3], channel)
+	default:
+		return fmt.Errorf("invalid -bridge spec %q, unknown transport %q", spec, parts[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	return bridge.New(slackTransport, other).Run()
+}
@@ -0,0 +1,86 @@
+// Package bridge copies messages bidirectionally between two chat
+// transports, so slackcat can act as a lightweight relay between Slack
+// and other chat systems (IRC, webhooks, and so on).
+package bridge
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// Transport is a chat backend that a Bridge can read from and write
+// to. Implementations are responsible for their own connection
+// management and for not echoing back messages they themselves sent.
+type Transport interface {
+	io.Reader
+	io.Writer
+	io.Closer
+
+	// Channels returns the channels this transport is currently
+	// relaying, for logging and loop-detection purposes.
+	Channels() []string
+}
+
+// Bridge relays messages between two Transports, one line at a time.
+type Bridge struct {
+	Left  Transport
+	Right Transport
+
+	// RewriteLeft and RewriteRight, if set, transform each line
+	// before it is written to the other side, e.g. to prefix a
+	// Slack username onto lines relayed to IRC.
+	RewriteLeft  func(line string) string
+	RewriteRight func(line string) string
+
+	// IncludeLeft and IncludeRight, if set, restrict relaying in
+	// that direction to lines matching the regexp.
+	IncludeLeft  *regexp.Regexp
+	IncludeRight *regexp.Regexp
+}
+
+// New returns a Bridge that relays messages between left and right in
+// both directions until Run returns.
+func New(left, right Transport) *Bridge {
+	return &Bridge{Left: left, Right: right}
+}
+
+// Run copies messages between the two transports until either side
+// returns an error (including io.EOF on Close). It blocks until the
+// bridge stops and returns the error that stopped it. When either
+// pump exits, both transports are closed so the other pump (and its
+// Transport) shuts down too, rather than being left running.
+func (b *Bridge) Run() error {
+	errs := make(chan error, 2)
+	go func() { errs <- pump(b.Left, b.Right, b.IncludeLeft, b.RewriteLeft) }()
+	go func() { errs <- pump(b.Right, b.Left, b.IncludeRight, b.RewriteRight) }()
+
+	first := <-errs
+	b.Left.Close()
+	b.Right.Close()
+	<-errs
+	return first
+}
+
+// pump copies lines from "from" to "to", applying include as a filter
+// and rewrite as a transform, until from is exhausted or errors.
+func pump(from, to Transport, include *regexp.Regexp, rewrite func(string) string) error {
+	scanner := bufio.NewScanner(from)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if include != nil && !include.MatchString(line) {
+			continue
+		}
+		if rewrite != nil {
+			line = rewrite(line)
+		}
+		if _, err := fmt.Fprintln(to, line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
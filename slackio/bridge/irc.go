@@ -0,0 +1,113 @@
+package bridge
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/goshuirc/irc-go/ircmsg"
+)
+
+// IRCTransport is a Transport backed by a single channel on an IRC
+// server. It speaks just enough of the protocol to register, join a
+// channel, and relay PRIVMSGs.
+type IRCTransport struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	nick    string
+	channel string
+
+	// pending holds the unread remainder of the last PRIVMSG, when p
+	// passed to Read wasn't large enough to take it all in one call.
+	pending []byte
+}
+
+// NewIRCTransport dials addr (host:port), registers as nick and joins
+// channel, returning a Transport for messages on that channel.
+func NewIRCTransport(addr, nick, channel string) (*IRCTransport, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	t := &IRCTransport{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		nick:    nick,
+		channel: channel,
+	}
+	fmt.Fprintf(conn, "NICK %s\r\n", nick)
+	fmt.Fprintf(conn, "USER %s 0 * :%s\r\n", nick, nick)
+	fmt.Fprintf(conn, "JOIN %s\r\n", channel)
+	return t, nil
+}
+
+// Channels implements Transport.
+func (t *IRCTransport) Channels() []string {
+	return []string{t.channel}
+}
+
+// Write sends each line of p as a PRIVMSG to the channel.
+func (t *IRCTransport) Write(p []byte) (n int, err error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(t.conn, "PRIVMSG %s :%s\r\n", t.channel, line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Read blocks until a PRIVMSG addressed to the channel arrives, then
+// returns its text followed by a newline, filling p across as many
+// calls as it takes if p is too small to take the whole line at once.
+// PINGs are answered transparently and messages from our own nick are
+// skipped, mirroring the selfUserID loop-detection in slackio.Reader.
+func (t *IRCTransport) Read(p []byte) (n int, err error) {
+	if len(t.pending) == 0 {
+		if err := t.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n = copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+// fill blocks until a PRIVMSG addressed to the channel arrives, and
+// stores its text followed by a newline in t.pending for Read to drain.
+func (t *IRCTransport) fill() error {
+	for {
+		line, err := t.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		msg, err := ircmsg.ParseLine(line)
+		if err != nil {
+			continue
+		}
+		switch msg.Command {
+		case "PING":
+			fmt.Fprintf(t.conn, "PONG :%s\r\n", msg.Params[0])
+		case "PRIVMSG":
+			if len(msg.Params) < 2 || msg.Params[0] != t.channel {
+				continue
+			}
+			if strings.HasPrefix(msg.Source, t.nick+"!") {
+				continue
+			}
+			t.pending = []byte(msg.Params[1] + "\n")
+			return nil
+		}
+	}
+}
+
+// Close disconnects from the IRC server.
+func (t *IRCTransport) Close() error {
+	fmt.Fprintf(t.conn, "QUIT\r\n")
+	return t.conn.Close()
+}
+
+var _ Transport = &IRCTransport{}
@@ -0,0 +1,59 @@
+package bridge
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/crewjam/slackcat/slackio"
+)
+
+// SlackTransport adapts a slackio.ReaderWriter to the Transport
+// interface. Self-message loop detection is already handled by
+// slackio.Reader's selfUserID check.
+type SlackTransport struct {
+	*slackio.ReaderWriter
+	channel string
+
+	// pending holds the unread remainder of the last message, when p
+	// passed to Read wasn't large enough to take it all in one call.
+	pending []byte
+}
+
+// NewSlackTransport connects to the given slack channel and returns a
+// Transport backed by it. url and token are passed through to
+// slackio.NewReaderWriter.
+func NewSlackTransport(url, token, channel string) (*SlackTransport, error) {
+	rw, err := slackio.NewReaderWriter(url, token, []string{channel})
+	if err != nil {
+		return nil, err
+	}
+	// ReadMessage only receives a message once the embedded text
+	// stream has taken it, so drain that stream in the background.
+	go io.Copy(ioutil.Discard, &rw.Reader)
+	return &SlackTransport{ReaderWriter: rw, channel: channel}, nil
+}
+
+// Channels implements Transport.
+func (s *SlackTransport) Channels() []string {
+	return []string{s.channel}
+}
+
+// Read implements Transport. It shadows the embedded ReaderWriter's
+// Read, which prefixes every line with "#channel: " for slackcat's own
+// io.Copy use — relaying that prefix onward to the other transport
+// would bake literal "#channel: " text into every bridged message. Read
+// messages via ReadMessage instead and return their raw text.
+func (s *SlackTransport) Read(p []byte) (n int, err error) {
+	if len(s.pending) == 0 {
+		msg, err := s.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		s.pending = []byte(msg.Text + "\n")
+	}
+	n = copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+var _ Transport = &SlackTransport{}
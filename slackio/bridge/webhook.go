@@ -0,0 +1,94 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// WebhookTransport relays messages to an outgoing webhook URL and
+// receives messages posted to an incoming webhook HTTP endpoint, in
+// the simple `{"text": "..."}` payload shape used by Slack-compatible
+// webhooks.
+type WebhookTransport struct {
+	outgoingURL string
+	channel     string
+	listener    net.Listener
+	pipeReader  *io.PipeReader
+	pipeWriter  *io.PipeWriter
+}
+
+// NewWebhookTransport starts an HTTP server on addr that accepts
+// incoming webhook posts at path, and relays Writes to outgoingURL.
+// channel is used only as the value Channels() reports.
+func NewWebhookTransport(addr, path, outgoingURL, channel string) (*WebhookTransport, error) {
+	pr, pw := io.Pipe()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	t := &WebhookTransport{
+		outgoingURL: outgoingURL,
+		channel:     channel,
+		listener:    listener,
+		pipeReader:  pr,
+		pipeWriter:  pw,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, t.handleIncoming)
+	go http.Serve(listener, mux)
+	return t, nil
+}
+
+func (t *WebhookTransport) handleIncoming(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fmt.Fprintf(t.pipeWriter, "%s\n", payload.Text)
+}
+
+// Read implements Transport.
+func (t *WebhookTransport) Read(p []byte) (int, error) {
+	return t.pipeReader.Read(p)
+}
+
+// Write posts p to the outgoing webhook URL as a JSON text payload.
+func (t *WebhookTransport) Write(p []byte) (int, error) {
+	body, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{Text: string(p)})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.Post(t.outgoingURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return len(p), nil
+}
+
+// Close implements Transport. It stops the incoming webhook HTTP
+// server as well as closing the pipe, so the listening socket and its
+// serve goroutine don't outlive the transport.
+func (t *WebhookTransport) Close() error {
+	err := t.listener.Close()
+	if pwErr := t.pipeWriter.Close(); err == nil {
+		err = pwErr
+	}
+	return err
+}
+
+// Channels implements Transport.
+func (t *WebhookTransport) Channels() []string {
+	return []string{t.channel}
+}
+
+var _ Transport = &WebhookTransport{}
@@ -0,0 +1,180 @@
+package slackio
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// EventsConfig configures a ReaderWriter backed by Slack's Events API
+// instead of the deprecated RTM connection. Use this when the bot
+// token can't call rtm.start, which is increasingly common for modern
+// Slack apps.
+type EventsConfig struct {
+	// URL is the endpoint of the slack API, which can be empty to
+	// use the default.
+	URL string
+	// Token is your slack token.
+	Token string
+	// Channels are the names of the channels (public, private, or
+	// DMs) to send to and receive from.
+	Channels []string
+	// SigningSecret, if set, is used to verify that callbacks to
+	// ListenAddr/Path actually came from Slack.
+	SigningSecret string
+	// ListenAddr is the address the event_callback HTTP server
+	// listens on, e.g. ":8080".
+	ListenAddr string
+	// Path is the HTTP path Slack's Event Subscriptions should be
+	// configured to post to, e.g. "/slack/events".
+	Path string
+}
+
+// NewEventsReaderWriter returns a ReaderWriter that receives messages
+// by running an HTTP server handling Slack's event_callback webhooks,
+// and sends via chat.postMessage.
+func NewEventsReaderWriter(cfg EventsConfig, opts ...Option) (*ReaderWriter, error) {
+	common, err := newCommonBase(cfg.URL, cfg.Token, cfg.Channels, opts...)
+	if err != nil {
+		return nil, err
+	}
+	common.broker, err = newEventsTransport(cfg.URL, cfg.Token, cfg.SigningSecret, cfg.ListenAddr, cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	s := &ReaderWriter{
+		common: common,
+		Reader: Reader{common: common},
+		Writer: Writer{common: common},
+	}
+	s.Reader.init()
+	return s, nil
+}
+
+// eventsTransport is a transport backed by an HTTP server receiving
+// Slack's event_callback webhooks, sending via chat.postMessage.
+type eventsTransport struct {
+	url           string
+	token         string
+	signingSecret string
+	server        *http.Server
+	listener      net.Listener
+	eventsCh      chan message
+	done          chan struct{}
+}
+
+func newEventsTransport(url, token, signingSecret, listenAddr, path string) (*eventsTransport, error) {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, err
+	}
+	t := &eventsTransport{
+		url:           url,
+		token:         token,
+		signingSecret: signingSecret,
+		listener:      listener,
+		eventsCh:      make(chan message),
+		done:          make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, t.handleCallback)
+	t.server = &http.Server{Handler: mux}
+	go t.server.Serve(listener)
+	return t, nil
+}
+
+// handleCallback handles both the one-time url_verification handshake
+// and ongoing event_callback deliveries for "message" events.
+func (t *eventsTransport) handleCallback(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !t.validSignature(r, body) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Event     struct {
+			Type    string `json:"type"`
+			Channel string `json:"channel"`
+			User    string `json:"user"`
+			Text    string `json:"text"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if payload.Type == "url_verification" {
+		fmt.Fprint(w, payload.Challenge)
+		return
+	}
+	if payload.Event.Type == "message" {
+		// If nothing is consuming Events() any more (e.g. the Reader
+		// that used to range over it has already exited), don't block
+		// here forever: that would keep this handler "active" and
+		// Close's server.Shutdown would never return.
+		select {
+		case t.eventsCh <- message{
+			channel: payload.Event.Channel,
+			user:    payload.Event.User,
+			text:    payload.Event.Text,
+		}:
+		case <-t.done:
+		}
+	}
+}
+
+// validSignature checks r's X-Slack-Signature header against body, as
+// described in https://api.slack.com/authentication/verifying-requests-from-slack.
+// When no signing secret is configured, requests are accepted unverified.
+func (t *eventsTransport) validSignature(r *http.Request, body []byte) bool {
+	if t.signingSecret == "" {
+		return true
+	}
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	mac := hmac.New(sha256.New, []byte(t.signingSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature")))
+}
+
+func (t *eventsTransport) Events() <-chan message {
+	return t.eventsCh
+}
+
+func (t *eventsTransport) Publish(channelID, text string) error {
+	params := url.Values{}
+	params.Set("channel", channelID)
+	params.Set("text", text)
+	_, err := apiPost(t.url, t.token, "chat.postMessage", params)
+	return err
+}
+
+// Close shuts down the HTTP server, waiting for any in-flight
+// handleCallback invocation to finish before closing eventsCh, so a
+// webhook delivery racing with Close can't send on a closed channel.
+// done is closed first so a handler blocked sending on eventsCh because
+// nothing is consuming Events() any more unblocks immediately, rather
+// than leaving Shutdown waiting on it forever.
+func (t *eventsTransport) Close() error {
+	close(t.done)
+	err := t.server.Shutdown(context.Background())
+	close(t.eventsCh)
+	return err
+}
+
+var _ transport = &eventsTransport{}
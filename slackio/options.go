@@ -0,0 +1,73 @@
+package slackio
+
+import "regexp"
+
+// Option configures a Reader, Writer, or ReaderWriter at construction
+// time. Pass zero or more to NewReader, NewWriter, or NewReaderWriter.
+type Option func(*readerWriterCommon)
+
+// defaultUploadThreshold is the default value for WithUploadThreshold:
+// comfortably under Slack's message size limits.
+const defaultUploadThreshold = 3500
+
+// WithUploadThreshold sets the byte threshold above which Write
+// auto-uploads its payload as a file via Upload instead of posting it
+// as a message. Pass 0 to disable auto-upload entirely.
+func WithUploadThreshold(n int) Option {
+	return func(c *readerWriterCommon) {
+		c.uploadThreshold = n
+	}
+}
+
+// WithUploadFiletype sets the Slack filetype (e.g. "text", "go",
+// "diff") used when Write auto-uploads a payload, and by every call to
+// Upload. Defaults to "text".
+func WithUploadFiletype(filetype string) Option {
+	return func(c *readerWriterCommon) {
+		c.uploadFiletype = filetype
+	}
+}
+
+// WithIncludeRegex restricts Reader to messages whose text matches re,
+// dropping everything else.
+func WithIncludeRegex(re *regexp.Regexp) Option {
+	return func(c *readerWriterCommon) {
+		c.includeRegex = re
+	}
+}
+
+// WithExcludeRegex drops any Reader message whose text matches re.
+func WithExcludeRegex(re *regexp.Regexp) Option {
+	return func(c *readerWriterCommon) {
+		c.excludeRegex = re
+	}
+}
+
+// WithUserAllowlist restricts Reader to messages posted by one of the
+// given slack user IDs.
+func WithUserAllowlist(userIDs ...string) Option {
+	return func(c *readerWriterCommon) {
+		allowlist := make(map[string]bool, len(userIDs))
+		for _, id := range userIDs {
+			allowlist[id] = true
+		}
+		c.userAllowlist = allowlist
+	}
+}
+
+// WithMentionOnly restricts Reader to messages that @-mention the
+// bot's own user ID.
+func WithMentionOnly() Option {
+	return func(c *readerWriterCommon) {
+		c.mentionOnly = true
+	}
+}
+
+// WithRateLimit limits Writer to at most msgsPerSec writes per second,
+// allowing bursts of up to burst writes, to stay within Slack's
+// tier-based rate limits.
+func WithRateLimit(msgsPerSec float64, burst int) Option {
+	return func(c *readerWriterCommon) {
+		c.rateLimiter = newRateLimiter(msgsPerSec, burst)
+	}
+}
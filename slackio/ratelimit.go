@@ -0,0 +1,45 @@
+package slackio
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a simple token-bucket limiter used by WithRateLimit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	tokens   float64
+	burst    float64
+	rate     float64 // tokens added per second
+	lastFill time.Time
+}
+
+func newRateLimiter(msgsPerSec float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		tokens:   float64(burst),
+		burst:    float64(burst),
+		rate:     msgsPerSec,
+		lastFill: time.Now(),
+	}
+}
+
+// Wait blocks until a token is available, then consumes it.
+func (r *rateLimiter) Wait() {
+	for {
+		r.mu.Lock()
+		now := time.Now()
+		r.tokens += now.Sub(r.lastFill).Seconds() * r.rate
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+		r.lastFill = now
+		if r.tokens >= 1 {
+			r.tokens--
+			r.mu.Unlock()
+			return
+		}
+		wait := time.Duration((1 - r.tokens) / r.rate * float64(time.Second))
+		r.mu.Unlock()
+		time.Sleep(wait)
+	}
+}
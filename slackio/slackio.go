@@ -1,8 +1,8 @@
 // Package slackio provides a reader and writer interface to Slack.
 //
-// Writing to a Writer emits messages to the configured Slack channel.
-// Reading from a Reader receives messages, separated by a newline from
-// a slack channel.
+// Writing to a Writer emits messages to the configured Slack channels.
+// Reading from a Reader receives messages, separated by a newline,
+// from those channels.
 //
 // Of course, these simple interfaces hide quite a bit of the richness
 // of the slack interface, but they are useful none the less for simple
@@ -10,7 +10,7 @@
 //
 // Example:
 //
-//    slack := NewReaderWriter("", slackToken, "general")
+//    slack, _ := NewReaderWriter("", slackToken, []string{"general"})
 //    defer slack.Close()
 //    fmt.Fprintf(slack, "Hello, World!")
 //    line, _, _ := bufio.NewReader(slack).ReadLine()
@@ -19,29 +19,51 @@
 package slackio
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 
 	"github.com/bobbytables/slacker"
 	"github.com/crewjam/errset"
 )
 
-// Reader reads messages from the specified slack channel. Create
+// readMessageBacklog bounds how many structured messages ReadMessage
+// can fall behind Read before older ones are dropped. Read's text
+// stream always receives every message; it's only the structured copy
+// that is best-effort when nothing is draining it.
+const readMessageBacklog = 256
+
+// ReceivedMessage is a single incoming message, in structured form.
+// See Reader.ReadMessage.
+type ReceivedMessage struct {
+	Channel string // name of the channel the message was posted to
+	User    string // slack user ID of the sender
+	Text    string
+}
+
+// Reader reads messages from the configured slack channel(s). Create
 // new instances with NewReader()
 type Reader struct {
 	common     *readerWriterCommon
 	pipeReader *io.PipeReader
 	pipeWriter *io.PipeWriter
+	msgCh      chan ReceivedMessage
 }
 
-// NewReader returns a new io.Reader that receives messages as they
-// are posted to a the specified slack channel. URL is the endpoint
-// of the slack server, which can be empty to use the default. token
-// is your slack token and channel is the name of the channel to
-// receive from.
-func NewReader(url, token, channel string) (*Reader, error) {
-	common, err := newIface(url, token, channel)
+// NewReader returns a new io.Reader that receives messages as they are
+// posted to the specified slack channels. URL is the endpoint of the
+// slack server, which can be empty to use the default. token is your
+// slack token and channels are the names of the channels (public,
+// private, or DMs) to receive from.
+func NewReader(url, token string, channels []string, opts ...Option) (*Reader, error) {
+	common, err := newIface(url, token, channels, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +76,7 @@ func NewReader(url, token, channel string) (*Reader, error) {
 
 func (s *Reader) init() {
 	s.pipeReader, s.pipeWriter = io.Pipe()
+	s.msgCh = make(chan ReceivedMessage, readMessageBacklog)
 	go s.reader()
 }
 
@@ -61,25 +84,40 @@ func (s *Reader) Read(p []byte) (n int, err error) {
 	return s.pipeReader.Read(p)
 }
 
+// ReadMessage returns the next message as a structured ReceivedMessage,
+// without the "#channel: " prefix that Read adds. It shares the same
+// underlying stream as Read: every message is first written to Read's
+// stream, which blocks until Read takes it, so ReadMessage receives
+// nothing unless Read is also being drained. If nothing is calling
+// ReadMessage, structured copies beyond readMessageBacklog are dropped.
+func (s *Reader) ReadMessage() (ReceivedMessage, error) {
+	msg, ok := <-s.msgCh
+	if !ok {
+		return ReceivedMessage{}, io.EOF
+	}
+	return msg, nil
+}
+
 func (s *Reader) reader() {
-	for {
-		event := <-s.common.broker.Events()
-		if event.Type == "message" {
-			msg, err := event.Message()
-			if err != nil {
-				s.pipeWriter.CloseWithError(err)
-			}
-			if msg.Channel != s.common.channelID {
-				continue
-			}
-			if msg.User == s.common.selfUserID {
-				continue
-			}
-			_, err = fmt.Fprintf(s.pipeWriter, "%s\n", msg.Text)
-			if err != nil {
-				s.pipeWriter.CloseWithError(err)
-				return
-			}
+	defer close(s.msgCh)
+	for event := range s.common.broker.Events() {
+		channelName, ok := s.common.channelNames[event.channel]
+		if !ok {
+			continue
+		}
+		if event.user == s.common.selfUserID {
+			continue
+		}
+		if !s.common.accept(event) {
+			continue
+		}
+		if _, err := fmt.Fprintf(s.pipeWriter, "#%s: %s\n", channelName, event.text); err != nil {
+			s.pipeWriter.CloseWithError(err)
+			return
+		}
+		select {
+		case s.msgCh <- ReceivedMessage{Channel: channelName, User: event.user, Text: event.text}:
+		default:
 		}
 	}
 }
@@ -91,18 +129,19 @@ func (s *Reader) Close() error {
 	return s.common.Close()
 }
 
-// Writer sends messages to the specified slack channel. Create
+// Writer sends messages to the configured slack channel(s). Create
 // new instances with NewWriter()
 type Writer struct {
 	common *readerWriterCommon
 }
 
-// NewWriter returns a new io.WriteCloser that sends message to the
-// the specified slack channel. URL is the endpoint of the slack server,
+// NewWriter returns a new io.WriteCloser that sends messages to the
+// specified slack channels. URL is the endpoint of the slack server,
 // which can be empty to use the default. token is your slack token and
-// channel is the name of the channel to receive from.
-func NewWriter(url, token, channel string) (*Writer, error) {
-	common, err := newIface(url, token, channel)
+// channels are the names of the channels (public, private, or DMs) to
+// send to.
+func NewWriter(url, token string, channels []string, opts ...Option) (*Writer, error) {
+	common, err := newIface(url, token, channels, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -112,16 +151,142 @@ func NewWriter(url, token, channel string) (*Writer, error) {
 	return s, nil
 }
 
+// Write broadcasts p to every configured channel. Use WriteTo to
+// target a single channel instead.
 func (s *Writer) Write(p []byte) (n int, err error) {
-	err = s.common.broker.Publish(slacker.RTMMessage{
-		Type:    "message",
-		Text:    string(p),
-		Channel: s.common.channelID,
-	})
-	if err != nil {
+	if s.common.uploadThreshold > 0 && len(p) > s.common.uploadThreshold {
+		if err := s.Upload("slackcat", "", "", bytes.NewReader(p)); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if s.common.rateLimiter != nil {
+		s.common.rateLimiter.Wait()
+	}
+	for _, channelID := range s.common.channelIDs {
+		if err := s.common.broker.Publish(channelID, string(p)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// WriteTo writes p to only the named channel, rather than broadcasting
+// it to all channels configured on the Writer as Write does. channel
+// must be one of the names passed to NewWriter/NewReaderWriter.
+func (s *Writer) WriteTo(channel string, p []byte) (n int, err error) {
+	channelID, ok := s.common.channelIDs[channel]
+	if !ok {
+		return 0, fmt.Errorf("not configured to write to channel %s", channel)
+	}
+	if s.common.rateLimiter != nil {
+		s.common.rateLimiter.Wait()
+	}
+	if s.common.uploadThreshold > 0 && len(p) > s.common.uploadThreshold {
+		if err := s.common.uploadFile([]string{channelID}, "slackcat", "", "", bytes.NewReader(p)); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if err := s.common.broker.Publish(channelID, string(p)); err != nil {
 		return 0, err
 	}
-	return len(p), err
+	return len(p), nil
+}
+
+// Upload posts the contents of r to every configured channel as a file
+// named filename, via the files.upload API. title and comment are
+// shown alongside the file in Slack's UI and may be left empty. The
+// filetype used is whatever was set with WithUploadFiletype, or "text"
+// if none was given.
+func (s *Writer) Upload(filename, title, comment string, r io.Reader) error {
+	if s.common.rateLimiter != nil {
+		s.common.rateLimiter.Wait()
+	}
+	channelIDs := make([]string, 0, len(s.common.channelIDs))
+	for _, id := range s.common.channelIDs {
+		channelIDs = append(channelIDs, id)
+	}
+	return s.common.uploadFile(channelIDs, filename, title, comment, r)
+}
+
+// Message is a richer representation of a slack message than the plain
+// text accepted by Write. Setting any of Username, IconURL, IconEmoji,
+// ThreadTS or Attachments causes WriteMessage to post via the
+// chat.postMessage API instead of through Write, since RTM cannot
+// carry these fields.
+type Message struct {
+	Text        string
+	Username    string
+	IconURL     string
+	IconEmoji   string
+	ThreadTS    string
+	Attachments []Attachment
+}
+
+// Attachment is a single slack message attachment, as accepted by
+// chat.postMessage. See https://api.slack.com/docs/message-attachments.
+type Attachment struct {
+	Title     string            `json:"title,omitempty"`
+	TitleLink string            `json:"title_link,omitempty"`
+	Text      string            `json:"text,omitempty"`
+	Color     string            `json:"color,omitempty"`
+	Fields    []AttachmentField `json:"fields,omitempty"`
+}
+
+// AttachmentField is a single field rendered in an Attachment.
+type AttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// WriteMessage sends msg to every configured channel. Plain text
+// messages (no username/icon/thread/attachment overrides) are sent
+// with Write, over the Writer's transport (RTM, or chat.postMessage
+// for the Events API). Anything that needs formatting Slack's RTM
+// protocol can't express is always sent via chat.postMessage.
+func (s *Writer) WriteMessage(msg Message) error {
+	if msg.Username == "" && msg.IconURL == "" && msg.IconEmoji == "" &&
+		msg.ThreadTS == "" && len(msg.Attachments) == 0 {
+		_, err := s.Write([]byte(msg.Text))
+		return err
+	}
+	return s.postMessage(msg)
+}
+
+func (s *Writer) postMessage(msg Message) error {
+	for _, channelID := range s.common.channelIDs {
+		if s.common.rateLimiter != nil {
+			s.common.rateLimiter.Wait()
+		}
+		params := url.Values{}
+		params.Set("channel", channelID)
+		params.Set("text", msg.Text)
+		if msg.Username != "" {
+			params.Set("username", msg.Username)
+		}
+		if msg.IconURL != "" {
+			params.Set("icon_url", msg.IconURL)
+		}
+		if msg.IconEmoji != "" {
+			params.Set("icon_emoji", msg.IconEmoji)
+		}
+		if msg.ThreadTS != "" {
+			params.Set("thread_ts", msg.ThreadTS)
+		}
+		if len(msg.Attachments) > 0 {
+			attachmentsJSON, err := json.Marshal(msg.Attachments)
+			if err != nil {
+				return err
+			}
+			params.Set("attachments", string(attachmentsJSON))
+		}
+		if _, err := apiPost(s.common.url, s.common.token, "chat.postMessage", params); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Close disconnects from the slack server
@@ -130,21 +295,22 @@ func (s *Writer) Close() error {
 }
 
 // ReaderWriter is implements the io.ReadCloser and io.WriteCloser interfaces
-// for slack channel. Writing to this object posts messages to the specified
-// slack channel. Reading from this object receives messages from the
-// specified slack channel. Create new instances with NewReaderWriter()
+// for slack channels. Writing to this object broadcasts messages to the
+// configured slack channels. Reading from this object receives messages
+// from those channels. Create new instances with NewReaderWriter()
 type ReaderWriter struct {
 	common *readerWriterCommon
 	Reader
 	Writer
 }
 
-// NewReaderWriter returns a new io.WriteCloser and io.ReadCloser that sends message
-// to and receives messages from the specified slack channel. URL is the
-// endpoint of the slack server, which can be empty to use the default. token is
-// your slack token and channel is the name of the channel to receive from.
-func NewReaderWriter(url, token, channel string) (*ReaderWriter, error) {
-	common, err := newIface(url, token, channel)
+// NewReaderWriter returns a new io.WriteCloser and io.ReadCloser that
+// sends messages to and receives messages from the specified slack
+// channels. URL is the endpoint of the slack server, which can be
+// empty to use the default. token is your slack token and channels are
+// the names of the channels (public, private, or DMs) to use.
+func NewReaderWriter(url, token string, channels []string, opts ...Option) (*ReaderWriter, error) {
+	common, err := newIface(url, token, channels, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -170,35 +336,70 @@ func (s *ReaderWriter) Close() error {
 }
 
 type readerWriterCommon struct {
-	channelID  string
-	selfUserID string
-	client     *slacker.APIClient
-	broker     *slacker.RTMBroker
+	url          string
+	token        string
+	channelIDs   map[string]string // channel name -> ID
+	channelNames map[string]string // channel ID -> name
+	selfUserID   string
+	client       *slacker.APIClient
+	broker       transport
+
+	uploadThreshold int
+	uploadFiletype  string
+
+	includeRegex  *regexp.Regexp
+	excludeRegex  *regexp.Regexp
+	userAllowlist map[string]bool
+	mentionOnly   bool
+	rateLimiter   *rateLimiter
+}
+
+// accept reports whether msg passes the configured include/exclude
+// regex, user allowlist, and mention-only filters.
+func (s *readerWriterCommon) accept(msg message) bool {
+	if s.includeRegex != nil && !s.includeRegex.MatchString(msg.text) {
+		return false
+	}
+	if s.excludeRegex != nil && s.excludeRegex.MatchString(msg.text) {
+		return false
+	}
+	if s.userAllowlist != nil && !s.userAllowlist[msg.user] {
+		return false
+	}
+	if s.mentionOnly && !strings.Contains(msg.text, "<@"+s.selfUserID+">") {
+		return false
+	}
+	return true
 }
 
-func newIface(url, token, channelName string) (*readerWriterCommon, error) {
-	s := &readerWriterCommon{}
+// newCommonBase resolves channelNames and the bot's own user ID, but
+// does not connect a transport. Callers pick the transport (RTM or
+// Events API) and assign it to the returned common.broker.
+func newCommonBase(url, token string, channelNames []string, opts ...Option) (*readerWriterCommon, error) {
+	s := &readerWriterCommon{
+		url:             url,
+		token:           token,
+		uploadThreshold: defaultUploadThreshold,
+		uploadFiletype:  "text",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
 	s.client = slacker.NewAPIClient(token, url)
 
-	// fetch the channel ID
-	s.channelID = ""
-	channels, err := s.client.ChannelsList()
+	channelIDs, err := resolveChannels(url, token, channelNames)
 	if err != nil {
 		return nil, err
 	}
-	for _, channel := range channels {
-		if channel.Name == channelName {
-			s.channelID = channel.ID
-			break
-		}
-	}
-	if s.channelID == "" {
-		return nil, fmt.Errorf("cannot find channel %s", channelName)
+	s.channelIDs = channelIDs
+	s.channelNames = make(map[string]string, len(channelIDs))
+	for name, id := range channelIDs {
+		s.channelNames[id] = name
 	}
 
 	// figure out what user we are connected as so we can ignore
 	// previous messages from that user.
-	authBuf, err := s.client.RunMethod("auth.test")
+	authBuf, err := apiPost(url, token, "auth.test", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -206,19 +407,78 @@ func newIface(url, token, channelName string) (*readerWriterCommon, error) {
 		UserID string `json:"user_id"`
 	}
 	json.Unmarshal(authBuf, &auth)
+	s.selfUserID = auth.UserID
+
+	return s, nil
+}
 
-	rtmStart, err := s.client.RTMStart()
+// resolveChannels looks up the channel ID for each of channelNames via
+// conversations.list, which (unlike channels.list) also covers private
+// channels, group DMs, and DMs. A DM is addressed by the other party's
+// slack user ID rather than a channel name.
+func resolveChannels(endpointURL, token string, channelNames []string) (map[string]string, error) {
+	wanted := make(map[string]bool, len(channelNames))
+	for _, name := range channelNames {
+		wanted[name] = true
+	}
+
+	ids := make(map[string]string, len(channelNames))
+	cursor := ""
+	for {
+		params := url.Values{}
+		params.Set("types", "public_channel,private_channel,mpim,im")
+		params.Set("limit", "1000")
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		respBuf, err := apiPost(endpointURL, token, "conversations.list", params)
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Channels []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+				User string `json:"user"` // set for IM (DM) conversations
+			} `json:"channels"`
+			ResponseMetadata struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"response_metadata"`
+		}
+		if err := json.Unmarshal(respBuf, &resp); err != nil {
+			return nil, err
+		}
+		for _, c := range resp.Channels {
+			if wanted[c.Name] {
+				ids[c.Name] = c.ID
+			}
+			if wanted[c.User] {
+				ids[c.User] = c.ID
+			}
+		}
+		cursor = resp.ResponseMetadata.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+
+	for _, name := range channelNames {
+		if ids[name] == "" {
+			return nil, fmt.Errorf("cannot find channel %s", name)
+		}
+	}
+	return ids, nil
+}
+
+func newIface(url, token string, channelNames []string, opts ...Option) (*readerWriterCommon, error) {
+	s, err := newCommonBase(url, token, channelNames, opts...)
 	if err != nil {
 		return nil, err
 	}
-	s.selfUserID = auth.UserID
-
-	s.broker = slacker.NewRTMBroker(rtmStart)
-	err = s.broker.Connect()
+	s.broker, err = newRTMTransport(s.client)
 	if err != nil {
 		return nil, err
 	}
-
 	return s, nil
 }
 
@@ -227,5 +487,111 @@ func (s *readerWriterCommon) Close() error {
 	return s.broker.Close()
 }
 
+// apiPost invokes a Slack Web API method directly over HTTP, POSTing
+// params form-encoded along with the token. slacker.APIClient.RunMethod
+// takes no params at all, so this is used for every method that needs
+// arguments beyond its own name (chat.postMessage, conversations.list,
+// auth.test, and so on). uploadFile below does the equivalent for
+// files.upload's multipart encoding.
+func apiPost(endpointURL, token, method string, params url.Values) ([]byte, error) {
+	endpoint := endpointURL
+	if endpoint == "" {
+		endpoint = "https://slack.com/api"
+	}
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("token", token)
+
+	req, err := http.NewRequest("POST", endpoint+"/"+method, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("%s: %s", method, result.Error)
+	}
+	return body, nil
+}
+
+// uploadFile posts r to the given channel IDs via files.upload (which
+// accepts a comma-separated channel list directly), a multipart
+// endpoint apiPost's form-encoded body can't express.
+func (s *readerWriterCommon) uploadFile(channelIDs []string, filename, title, comment string, r io.Reader) error {
+	endpoint := s.url
+	if endpoint == "" {
+		endpoint = "https://slack.com/api"
+	}
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	fields := map[string]string{
+		"token":           s.token,
+		"channels":        strings.Join(channelIDs, ","),
+		"filename":        filename,
+		"filetype":        s.uploadFiletype,
+		"title":           title,
+		"initial_comment": comment,
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := form.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	filePart, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(filePart, r); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", endpoint+"/files.upload", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("files.upload: %s", result.Error)
+	}
+	return nil
+}
+
 var _ io.ReadCloser = &Reader{}
 var _ io.WriteCloser = &Writer{}
@@ -0,0 +1,80 @@
+package slackio
+
+import "github.com/bobbytables/slacker"
+
+// message is a single incoming chat message, in the minimal shape both
+// the RTM and Events API transports produce.
+type message struct {
+	channel string
+	user    string
+	text    string
+}
+
+// transport abstracts the two ways slackio can send and receive
+// messages: the (deprecated) RTM connection, and the Events API.
+type transport interface {
+	// Events returns the channel messages arrive on. It is closed
+	// when the transport disconnects.
+	Events() <-chan message
+	// Publish sends text to the given channel ID.
+	Publish(channelID, text string) error
+	// Close disconnects the transport.
+	Close() error
+}
+
+// rtmTransport is a transport backed by slacker's RTM broker.
+type rtmTransport struct {
+	broker   *slacker.RTMBroker
+	eventsCh chan message
+}
+
+// newRTMTransport starts an RTM connection for client and begins
+// translating its events into the transport's message shape.
+func newRTMTransport(client *slacker.APIClient) (*rtmTransport, error) {
+	rtmStart, err := client.RTMStart()
+	if err != nil {
+		return nil, err
+	}
+	broker := slacker.NewRTMBroker(rtmStart)
+	if err := broker.Connect(); err != nil {
+		return nil, err
+	}
+	t := &rtmTransport{
+		broker:   broker,
+		eventsCh: make(chan message),
+	}
+	go t.pump()
+	return t, nil
+}
+
+func (t *rtmTransport) pump() {
+	for event := range t.broker.Events() {
+		if event.Type != "message" {
+			continue
+		}
+		msg, err := event.Message()
+		if err != nil {
+			continue
+		}
+		t.eventsCh <- message{channel: msg.Channel, user: msg.User, text: msg.Text}
+	}
+	close(t.eventsCh)
+}
+
+func (t *rtmTransport) Events() <-chan message {
+	return t.eventsCh
+}
+
+func (t *rtmTransport) Publish(channelID, text string) error {
+	return t.broker.Publish(slacker.RTMMessage{
+		Type:    "message",
+		Text:    text,
+		Channel: channelID,
+	})
+}
+
+func (t *rtmTransport) Close() error {
+	return t.broker.Close()
+}
+
+var _ transport = &rtmTransport{}